@@ -0,0 +1,118 @@
+package cache
+
+// cache/aof.go
+//
+// AOF compaction: RewriteAOF collapses the log down to one SET line per
+// live entry so ReplayLog doesn't have to wade through years of
+// superseded SETs and already-applied DELETEs/EXPIREs. WithAutoRewrite
+// triggers this in the background once the log has grown past a
+// threshold and at least doubled since the last rewrite.
+
+import (
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// RewriteAOF compacts the AOF at path down to a single SET line per live
+// entry - recomputing each entry's remaining TTL from its current
+// expiresAt - then atomically replaces the log at path with it.
+func (kv *OpenCache[K, V]) RewriteAOF(path string) error {
+	kv.Mu.Lock()
+	defer kv.Mu.Unlock()
+
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for key, ent := range kv.Cache {
+		var ttl *time.Duration
+		if ent.expiresAt != nil {
+			remaining := ent.expiresAt.Sub(now)
+			if remaining <= 0 {
+				// Expired but not yet reaped; let the reaper/Get path
+				// handle it rather than persisting a dead entry.
+				continue
+			}
+			ttl = &remaining
+		}
+
+		logEntry, err := kv.makeLogEntry("SET", key, ent.value, ttl)
+		if err != nil {
+			kv.Logger.Errorw("Failed to encode AOF entry during rewrite", "key", key, "error", err)
+			continue
+		}
+		data, err := json.Marshal(logEntry)
+		if err != nil {
+			kv.Logger.Errorw("Failed to encode AOF entry during rewrite", "key", key, "error", err)
+			continue
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			f.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	kv.LogPath = &path
+
+	if info, err := os.Stat(path); err == nil {
+		atomic.StoreInt64(&kv.lastRewriteSize, info.Size())
+	}
+
+	kv.Logger.Debugw("Rewrote AOF", "path", path, "entries", len(kv.Cache))
+	return nil
+}
+
+// WithAutoRewrite enables background AOF compaction: once the log file
+// exceeds threshold bytes and has at least doubled since the last
+// rewrite, RewriteAOF runs in its own goroutine so it never blocks a
+// Set/Delete call.
+func WithAutoRewrite[K comparable, V any](threshold int64) Option[K, V] {
+	return func(kv *OpenCache[K, V]) { kv.autoRewriteThreshold = threshold }
+}
+
+// maybeAutoRewrite checks the AOF's on-disk size and kicks off a
+// background RewriteAOF if it has grown past the configured threshold
+// and doubled since the last rewrite. Safe to call with kv.Mu held; the
+// rewrite itself acquires the lock later, from its own goroutine.
+func (kv *OpenCache[K, V]) maybeAutoRewrite() {
+	if kv.autoRewriteThreshold <= 0 || kv.LogPath == nil {
+		return
+	}
+
+	info, err := os.Stat(*kv.LogPath)
+	if err != nil {
+		return
+	}
+	size := info.Size()
+	if size < kv.autoRewriteThreshold {
+		return
+	}
+	if size < 2*atomic.LoadInt64(&kv.lastRewriteSize) {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&kv.rewriting, 0, 1) {
+		return // a rewrite is already in flight
+	}
+
+	path := *kv.LogPath
+	go func() {
+		defer atomic.StoreInt32(&kv.rewriting, 0)
+		if err := kv.RewriteAOF(path); err != nil {
+			kv.Logger.Errorw("Background AOF rewrite failed", "path", path, "error", err)
+		}
+	}()
+}
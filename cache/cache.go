@@ -4,7 +4,7 @@ package cache
 
 import (
 	"bufio"
-	"container/list"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,28 +16,111 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-type entry struct {
-	key       interface{}
-	value     interface{}
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
 	expiresAt *time.Time
+	size      int64
+	heapItem  *expirationItem[K]
 }
 
-type OpenCache struct {
-	Cache      map[interface{}]*list.Element
+// OpenCache is a generic, thread-safe cache with a pluggable eviction
+// policy, active TTL expiration, and AOF-based persistence.
+type OpenCache[K comparable, V any] struct {
+	Cache      map[K]*entry[K, V]
 	Mu         sync.Mutex
-	LRU_deque  *list.List
+	Policy     EvictionPolicy[K]
 	Capacity   int
 	Persistent bool
 	LogPath    *string
 	Logger     *zap.SugaredLogger
+
+	// MarshalKey/UnmarshalKey control how keys are encoded into the AOF so
+	// that persistence keeps working for arbitrary comparable key types.
+	// They default to JSON encoding.
+	MarshalKey   func(K) (string, error)
+	UnmarshalKey func(string) (K, error)
+
+	// MaxBytes/Sizer switch the cache into memory-bounded mode (see
+	// NewOpenCacheWithBytes): Capacity is ignored and entries are evicted
+	// whenever CurrentBytes exceeds MaxBytes. Sizer is nil in entry-count
+	// mode.
+	MaxBytes     int64
+	CurrentBytes int64
+	Sizer        func(k, v any) int64
+
+	// expireQueue backs the active-expiration reaper goroutine started by
+	// startReaper; wake nudges it to recompute its sleep, ctx/cancel tear
+	// it down from Close.
+	expireQueue expirationQueue[K]
+	wake        chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	// Lifecycle hooks, set via the With* options and dispatched by
+	// runHooks off of hookCh. See cache/hooks.go.
+	onInsertion  func(key K, value V)
+	onEviction   func(key K, value V, reason EvictionReason)
+	onExpiration func(key K, value V)
+	onDeletion   func(key K, value V)
+	hookCh       chan hookEvent[K, V]
+
+	// autoRewriteThreshold/lastRewriteSize/rewriting back WithAutoRewrite;
+	// see cache/aof.go.
+	autoRewriteThreshold int64
+	lastRewriteSize      int64
+	rewriting            int32
 }
 
-// constructor
-func NewOpenCache(capacity int, persistent bool, logPath string) *OpenCache {
-	if capacity < 1 {
-		capacity = 1
+// AnyCache is a deprecated wrapper for callers migrating off the
+// pre-generics interface{}-keyed API. Prefer OpenCache[K, V] directly.
+//
+// Unlike OpenCache[K, V], AnyCache can't rely on a comparable type
+// parameter to rule out nil and dynamically non-comparable keys at
+// compile time, so it re-validates keys on every call the same way the
+// pre-generics API did.
+//
+// Deprecated: use OpenCache[K, V] with concrete type parameters instead.
+type AnyCache struct {
+	*OpenCache[interface{}, interface{}]
+}
+
+// NewAnyCache constructs the legacy interface{}-keyed cache.
+//
+// Deprecated: use NewOpenCache[K, V] with concrete type parameters instead.
+func NewAnyCache(capacity int, persistent bool, logPath string) *AnyCache {
+	return &AnyCache{NewOpenCache[interface{}, interface{}](capacity, persistent, logPath, LRU)}
+}
+
+// Get re-validates key before delegating, since a dynamically non-comparable
+// key would otherwise panic deep inside the map lookup.
+func (kv *AnyCache) Get(key interface{}) (interface{}, bool) {
+	if err := internal.ValidateKey(key); err != nil {
+		return nil, false
+	}
+	return kv.OpenCache.Get(key)
+}
+
+// Set re-validates key before delegating, since a dynamically non-comparable
+// key would otherwise panic deep inside the map write.
+func (kv *AnyCache) Set(key interface{}, value interface{}, ttl_duration *time.Duration) bool {
+	if err := internal.ValidateKey(key); err != nil {
+		return false
+	}
+	return kv.OpenCache.Set(key, value, ttl_duration)
+}
+
+// Delete re-validates key before delegating, since a dynamically
+// non-comparable key would otherwise panic deep inside the map lookup.
+func (kv *AnyCache) Delete(key interface{}) bool {
+	if err := internal.ValidateKey(key); err != nil {
+		return false
 	}
+	return kv.OpenCache.Delete(key)
+}
 
+// newCacheLogger builds the shared zap logger used by every constructor.
+func newCacheLogger() *zap.SugaredLogger {
 	logFile, _ := os.OpenFile("opencache.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
 	// using uber's zap logger library
@@ -61,111 +144,178 @@ func NewOpenCache(capacity int, persistent bool, logPath string) *OpenCache {
 		// to print to terminal use os.Stdout
 		zap.DebugLevel,
 	)
-	logger := zap.New(core).Sugar()
+	return zap.New(core).Sugar()
+}
+
+// constructor
+func NewOpenCache[K comparable, V any](capacity int, persistent bool, logPath string, policy Policy, opts ...Option[K, V]) *OpenCache[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	kv := &OpenCache[K, V]{
+		Cache:        make(map[K]*entry[K, V]),
+		Policy:       newPolicy[K](policy, capacity),
+		Capacity:     capacity,
+		Persistent:   persistent,
+		LogPath:      &logPath,
+		Logger:       newCacheLogger(),
+		MarshalKey:   defaultMarshalKey[K],
+		UnmarshalKey: defaultUnmarshalKey[K],
+	}
+	for _, opt := range opts {
+		opt(kv)
+	}
+	kv.startReaper()
+	kv.startHooks()
+	return kv
+}
+
+// NewOpenCacheWithBytes builds a memory-bounded cache: instead of capping
+// the number of entries, it evicts whenever the estimated size of all
+// entries exceeds maxBytes. maxBytes is typically produced by ParseSize
+// (e.g. ParseSize("64MB")). If sizer is nil, DefaultSizer is used.
+func NewOpenCacheWithBytes[K comparable, V any](maxBytes int64, sizer func(k, v any) int64, persistent bool, logPath string, policy Policy, opts ...Option[K, V]) *OpenCache[K, V] {
+	if maxBytes < 1 {
+		maxBytes = 1
+	}
+	if sizer == nil {
+		sizer = DefaultSizer
+	}
 
-	return &OpenCache{
-		Cache:      make(map[interface{}]*list.Element),
-		LRU_deque:  list.New(),
-		Capacity:   capacity,
-		Persistent: persistent,
-		LogPath:    &logPath,
-		Logger:     logger,
+	// The eviction policy still needs a capacity hint to size its internal
+	// ghost lists (ARC) - there's no entry-count cap in this mode, so fall
+	// back to a generous placeholder that keeps ghost bookkeeping bounded.
+	const arcGhostHint = 1 << 16
+
+	kv := &OpenCache[K, V]{
+		Cache:        make(map[K]*entry[K, V]),
+		Policy:       newPolicy[K](policy, arcGhostHint),
+		Persistent:   persistent,
+		LogPath:      &logPath,
+		Logger:       newCacheLogger(),
+		MarshalKey:   defaultMarshalKey[K],
+		UnmarshalKey: defaultUnmarshalKey[K],
+		MaxBytes:     maxBytes,
+		Sizer:        sizer,
+	}
+	for _, opt := range opts {
+		opt(kv)
 	}
+	kv.startReaper()
+	kv.startHooks()
+	return kv
 }
 
 // flush all logs
-func (kv *OpenCache) Close() {
+func (kv *OpenCache[K, V]) Close() {
+	if kv.cancel != nil {
+		kv.cancel() // stop the TTL reaper goroutine
+	}
 	_ = kv.Logger.Sync() // flush logs
 }
 
-func (kv *OpenCache) Get(key interface{}) (interface{}, bool) {
+func (kv *OpenCache[K, V]) Get(key K) (V, bool) {
 	kv.Mu.Lock()
 	defer kv.Mu.Unlock()
 
-	// validate key first
-	if err := internal.ValidateKey(key); err != nil {
-		// should be in key value pairs
-		kv.Logger.Debugw("Invalid key rejected", "key", key)
-		return nil, false
-	}
+	var zero V
 
-	// update deque ordering
-	if elem, ok := kv.Cache[key]; ok {
-
-		entry := elem.Value.(*entry)
-		if entry.expiresAt != nil && time.Now().After(*entry.expiresAt) {
-			// if it is past expiration date, then remove from cache and deque
-			delete(kv.Cache, entry.key)
-			kv.LRU_deque.Remove(elem)
+	if ent, ok := kv.Cache[key]; ok {
+		if ent.expiresAt != nil && time.Now().After(*ent.expiresAt) {
+			// if it is past expiration date, then remove from cache
+			delete(kv.Cache, ent.key)
+			kv.Policy.OnRemove(ent.key)
+			kv.invalidateExpiry(ent)
+			if kv.Sizer != nil {
+				kv.CurrentBytes -= ent.size
+			}
 
 			kv.Logger.Debugw("Deleted entry due to TTL expiration",
-				"key", entry.key,
-				"expiresAt", entry.expiresAt,
+				"key", ent.key,
+				"expiresAt", ent.expiresAt,
 			)
+			kv.emitEviction(ent.key, ent.value, ReasonTTL)
+			kv.emitExpiration(ent.key, ent.value)
+
+			if kv.Persistent {
+				if logEntry, err := kv.makeLogEntry("EXPIRE", ent.key, ent.value, nil); err == nil {
+					kv.AppendToLog(logEntry)
+				} else {
+					kv.Logger.Errorw("Failed to encode AOF entry", "key", ent.key, "error", err)
+				}
+			}
 
-			return nil, false
+			return zero, false
 		}
 
-		kv.LRU_deque.MoveToFront(elem)
+		kv.Policy.OnAccess(key)
 
-		kv.Logger.Debugw("Moved entry to front of LRU",
-			"key", entry.key,
+		kv.Logger.Debugw("Recorded cache hit",
+			"key", ent.key,
 		)
-		return entry.value, true
+		return ent.value, true
 	}
-	return nil, false
+	return zero, false
 }
 
-func (kv *OpenCache) Set(key interface{}, value interface{}, ttl_duration *time.Duration) bool {
+func (kv *OpenCache[K, V]) Set(key K, value V, ttl_duration *time.Duration) bool {
 	kv.Mu.Lock()
 	defer kv.Mu.Unlock()
 
-	// validate key first
-	if err := internal.ValidateKey(key); err != nil {
-		kv.Logger.Debugw("Invalid key rejected", "key", key)
-		return false
-	}
-
-	if elem, ok := kv.Cache[key]; ok {
-		ent := elem.Value.(*entry)
+	if ent, ok := kv.Cache[key]; ok {
+		oldValue := ent.value
 		ent.value = value
 		if ttl_duration != nil {
 			exp := time.Now().Add(*ttl_duration)
 			ent.expiresAt = &exp
+			kv.scheduleExpiry(key, ent, exp)
 		} else {
 			ent.expiresAt = nil
+			kv.invalidateExpiry(ent)
+		}
+		if kv.Sizer != nil {
+			kv.CurrentBytes -= ent.size
+			ent.size = kv.Sizer(key, value)
+			kv.CurrentBytes += ent.size
 		}
 
-		kv.LRU_deque.MoveToFront(elem)
+		kv.Policy.OnAccess(key)
 
-		kv.Logger.Debugw("Moved entry to front of LRU",
+		kv.Logger.Debugw("Recorded cache hit",
 			"key", key,
 		)
+		kv.emitEviction(key, oldValue, ReasonReplaced)
+		kv.enforceBudget()
 
 		// persist update
 		if kv.Persistent {
-			kv.AppendToLog(makeLogEntry("SET", key, value, ttl_duration))
-			kv.Logger.Debugw("Persisted to AOF (SET)", "key", key, "value", value)
+			if logEntry, err := kv.makeLogEntry("SET", key, value, ttl_duration); err == nil {
+				kv.AppendToLog(logEntry)
+				kv.Logger.Debugw("Persisted to AOF (SET)", "key", key, "value", value)
+				kv.maybeAutoRewrite()
+			} else {
+				kv.Logger.Errorw("Failed to encode AOF entry", "key", key, "error", err)
+			}
 		}
 
 		return true
 	}
 
-	// reached capacity for deque
-	if kv.LRU_deque.Len() >= kv.Capacity {
-
-		// right end of deque
-		back := kv.LRU_deque.Back()
-		if back != nil {
-
-			evicted := back.Value.(*entry)
-			delete(kv.Cache, evicted.key)
-			kv.LRU_deque.Remove(back)
+	// reached capacity (entry-count mode only; byte-bounded mode is
+	// enforced below once the new entry's size is known)
+	if kv.Sizer == nil && len(kv.Cache) >= kv.Capacity {
+		if evictedKey, ok := kv.Policy.Evict(); ok {
+			evicted, existed := kv.Cache[evictedKey]
+			delete(kv.Cache, evictedKey)
 
 			kv.Logger.Debugw("Deleted entry due to capacity",
-				"key", evicted.key,
+				"key", evictedKey,
 			)
-
+			if existed {
+				kv.invalidateExpiry(evicted)
+				kv.emitEviction(evictedKey, evicted.value, ReasonCapacity)
+			}
 		}
 	}
 
@@ -175,48 +325,72 @@ func (kv *OpenCache) Set(key interface{}, value interface{}, ttl_duration *time.
 		expPtr = &exp
 	}
 
-	elem := kv.LRU_deque.PushFront(&entry{
+	var size int64
+	if kv.Sizer != nil {
+		size = kv.Sizer(key, value)
+		kv.CurrentBytes += size
+	}
+
+	ent := &entry[K, V]{
 		key:       key,
 		value:     value,
 		expiresAt: expPtr,
-	})
-	kv.Cache[key] = elem
+		size:      size,
+	}
+	kv.Cache[key] = ent
+	kv.Policy.OnInsert(key)
+	if expPtr != nil {
+		kv.scheduleExpiry(key, ent, *expPtr)
+	}
 
-	kv.Logger.Debugw("Moved entry to front of LRU",
+	kv.Logger.Debugw("Inserted new entry",
 		"key", key,
 	)
+	kv.emitInsertion(key, value)
+	kv.enforceBudget()
 
 	// persist update
 	if kv.Persistent {
-		kv.AppendToLog(makeLogEntry("SET", key, value, ttl_duration))
-		kv.Logger.Debugw("Persisted to AOF (SET)", "key", key, "value", value)
+		if logEntry, err := kv.makeLogEntry("SET", key, value, ttl_duration); err == nil {
+			kv.AppendToLog(logEntry)
+			kv.Logger.Debugw("Persisted to AOF (SET)", "key", key, "value", value)
+			kv.maybeAutoRewrite()
+		} else {
+			kv.Logger.Errorw("Failed to encode AOF entry", "key", key, "error", err)
+		}
 	}
 
 	return true
 }
 
-func (kv *OpenCache) Delete(key interface{}) bool {
+func (kv *OpenCache[K, V]) Delete(key K) bool {
 	kv.Mu.Lock()
 	defer kv.Mu.Unlock()
 
-	// validate key first
-	if err := internal.ValidateKey(key); err != nil {
-		kv.Logger.Debugw("Invalid key rejected", "key", key)
-		return false
-	}
-
-	if elem, ok := kv.Cache[key]; ok {
-		kv.LRU_deque.Remove(elem)
+	if ent, ok := kv.Cache[key]; ok {
+		kv.Policy.OnRemove(key)
 		delete(kv.Cache, key)
+		kv.invalidateExpiry(ent)
+		if kv.Sizer != nil {
+			kv.CurrentBytes -= ent.size
+		}
 
 		kv.Logger.Debugw("Deleted entry from cache",
 			"key", key,
 		)
+		kv.emitEviction(key, ent.value, ReasonManual)
+		kv.emitDeletion(key, ent.value)
 
 		// persist delete
 		if kv.Persistent {
-			kv.AppendToLog(makeLogEntry("DELETE", key, nil, nil))
-			kv.Logger.Debugw("Persisted to AOF (DELETE)", "key", key)
+			var zero V
+			if logEntry, err := kv.makeLogEntry("DELETE", key, zero, nil); err == nil {
+				kv.AppendToLog(logEntry)
+				kv.Logger.Debugw("Persisted to AOF (DELETE)", "key", key)
+				kv.maybeAutoRewrite()
+			} else {
+				kv.Logger.Errorw("Failed to encode AOF entry", "key", key, "error", err)
+			}
 		}
 		return true
 	}
@@ -224,32 +398,71 @@ func (kv *OpenCache) Delete(key interface{}) bool {
 	return false
 }
 
-func (kv *OpenCache) Len() int {
+func (kv *OpenCache[K, V]) Len() int {
 	kv.Mu.Lock()
 	defer kv.Mu.Unlock()
 
 	return len(kv.Cache)
 }
 
-func (kv *OpenCache) Print() {
+// BytesUsed reports the estimated size, in bytes, of everything currently
+// in the cache. It is only meaningful for caches built with
+// NewOpenCacheWithBytes; entry-count-bounded caches always report 0.
+func (kv *OpenCache[K, V]) BytesUsed() int64 {
+	kv.Mu.Lock()
+	defer kv.Mu.Unlock()
+
+	return kv.CurrentBytes
+}
+
+// enforceBudget evicts entries, per the active policy, until CurrentBytes
+// is back under MaxBytes. It is a no-op for entry-count-bounded caches. Must
+// be called with kv.Mu held.
+func (kv *OpenCache[K, V]) enforceBudget() {
+	if kv.Sizer == nil {
+		return
+	}
+
+	for kv.CurrentBytes > kv.MaxBytes {
+		evictedKey, ok := kv.Policy.Evict()
+		if !ok {
+			break
+		}
+		evicted, exists := kv.Cache[evictedKey]
+		if !exists {
+			continue
+		}
+		delete(kv.Cache, evictedKey)
+		kv.CurrentBytes -= evicted.size
+		kv.invalidateExpiry(evicted)
+
+		kv.Logger.Debugw("Deleted entry to stay under byte budget",
+			"key", evictedKey,
+			"bytesUsed", kv.CurrentBytes,
+			"maxBytes", kv.MaxBytes,
+		)
+		kv.emitEviction(evictedKey, evicted.value, ReasonBytes)
+	}
+}
+
+func (kv *OpenCache[K, V]) Print() {
 	fmt.Println("\nSTART PRINT-")
-	for k, elem := range kv.Cache {
-		e := elem.Value.(*entry).value
-		fmt.Printf("Key: %v, Value: %v\n", k, e)
+	for k, ent := range kv.Cache {
+		fmt.Printf("Key: %v, Value: %v\n", k, ent.value)
 	}
 	fmt.Println("END PRINT-")
 }
 
 // persistence struct + funcs
 type LogEntry struct {
-	Op    string      `json:"op"`
-	Key   string      `json:"key"`
-	Value interface{} `json:"value,omitempty"`
-	TTLms int64       `json:"ttl_ms,omitempty"`
+	Op    string          `json:"op"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	TTLms int64           `json:"ttl_ms,omitempty"`
 }
 
 // for every set and delete op, we append to aof file
-func (kv *OpenCache) AppendToLog(entry LogEntry) {
+func (kv *OpenCache[K, V]) AppendToLog(entry LogEntry) {
 	if len(*kv.LogPath) == 0 {
 		tmp := "appendonly.aof"
 		kv.LogPath = &tmp
@@ -266,8 +479,11 @@ func (kv *OpenCache) AppendToLog(entry LogEntry) {
 	f.Write(append(data, '\n'))
 }
 
-// this is just to reset and repopulate the cache given the logfile
-func (kv *OpenCache) ReplayLog(filename string) error {
+// ReplayLog repopulates the cache from filename. It makes no assumption
+// about the log's shape: an interleaved history of SETs/DELETEs/EXPIREs
+// and a compacted RewriteAOF snapshot (one SET per live key) replay the
+// same way, since each op is just applied in order.
+func (kv *OpenCache[K, V]) ReplayLog(filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -283,28 +499,54 @@ func (kv *OpenCache) ReplayLog(filename string) error {
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		var entry LogEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		var logEntry LogEntry
+		if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
+			continue
+		}
+
+		key, err := kv.UnmarshalKey(logEntry.Key)
+		if err != nil {
+			kv.Logger.Errorw("Failed to decode AOF key", "key", logEntry.Key, "error", err)
 			continue
 		}
 
-		switch entry.Op {
+		switch logEntry.Op {
 		case "SET":
+			var value V
+			if len(logEntry.Value) > 0 {
+				if err := json.Unmarshal(logEntry.Value, &value); err != nil {
+					kv.Logger.Errorw("Failed to decode AOF value", "key", logEntry.Key, "error", err)
+					continue
+				}
+			}
 			var ttl *time.Duration
-			if entry.TTLms > 0 {
-				t := time.Duration(entry.TTLms) * time.Millisecond
+			if logEntry.TTLms > 0 {
+				t := time.Duration(logEntry.TTLms) * time.Millisecond
 				ttl = &t
 			}
-			kv.Set(entry.Key, entry.Value, ttl)
-		case "DELETE":
-			kv.Delete(entry.Key)
+			kv.Set(key, value, ttl)
+		case "DELETE", "EXPIRE":
+			kv.Delete(key)
 		}
 	}
 	return scanner.Err()
 }
 
-func makeLogEntry(op string, key interface{}, value interface{}, ttl *time.Duration) LogEntry {
-	strKey := key.(string)
+func (kv *OpenCache[K, V]) makeLogEntry(op string, key K, value V, ttl *time.Duration) (LogEntry, error) {
+	strKey, err := kv.MarshalKey(key)
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	var rawValue json.RawMessage
+	if op == "SET" {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return LogEntry{}, err
+		}
+		rawValue = data
+	}
+
 	var ttlms int64
 	if ttl != nil {
 		ttlms = ttl.Milliseconds()
@@ -312,7 +554,21 @@ func makeLogEntry(op string, key interface{}, value interface{}, ttl *time.Durat
 	return LogEntry{
 		Op:    op,
 		Key:   strKey,
-		Value: value,
+		Value: rawValue,
 		TTLms: ttlms,
+	}, nil
+}
+
+func defaultMarshalKey[K comparable](key K) (string, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
+}
+
+func defaultUnmarshalKey[K comparable](raw string) (K, error) {
+	var key K
+	err := json.Unmarshal([]byte(raw), &key)
+	return key, err
 }
@@ -0,0 +1,417 @@
+package cache
+
+// cache/eviction.go
+//
+// Pluggable eviction policies for OpenCache. A policy only ever deals with
+// keys: OpenCache itself remains the source of truth for key -> value
+// storage, while a policy tracks just enough bookkeeping to decide, in
+// OnAccess/OnInsert, how "hot" a key is, and in Evict, which key should be
+// reclaimed next.
+
+import "container/list"
+
+// Policy selects which EvictionPolicy implementation NewOpenCache wires up.
+type Policy int
+
+const (
+	LRU Policy = iota
+	SIEVE
+	LFU
+	ARC
+)
+
+// EvictionPolicy decides eviction order for a cache. All methods are called
+// with the owning OpenCache's mutex already held, so implementations do not
+// need their own locking.
+type EvictionPolicy[K comparable] interface {
+	// OnAccess records a cache hit (Get, or Set of an already-present key).
+	OnAccess(key K)
+	// OnInsert records a brand-new key being added to the cache.
+	OnInsert(key K)
+	// OnRemove drops bookkeeping for a key that left the cache via Delete,
+	// TTL expiration, or replacement.
+	OnRemove(key K)
+	// Evict picks a key to reclaim and removes it from the policy's own
+	// bookkeeping. It returns false if there is nothing left to evict.
+	Evict() (K, bool)
+}
+
+func newPolicy[K comparable](p Policy, capacity int) EvictionPolicy[K] {
+	switch p {
+	case SIEVE:
+		return newSievePolicy[K]()
+	case LFU:
+		return newLFUPolicy[K]()
+	case ARC:
+		return newARCPolicy[K](capacity)
+	default:
+		return newLRUPolicy[K]()
+	}
+}
+
+// --- LRU -------------------------------------------------------------
+
+type lruPolicy[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+}
+
+func newLRUPolicy[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{
+		order: list.New(),
+		elems: make(map[K]*list.Element),
+	}
+}
+
+func (p *lruPolicy[K]) OnAccess(key K) {
+	if e, ok := p.elems[key]; ok {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K]) OnInsert(key K) {
+	p.elems[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy[K]) OnRemove(key K) {
+	if e, ok := p.elems[key]; ok {
+		p.order.Remove(e)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lruPolicy[K]) Evict() (K, bool) {
+	var zero K
+	back := p.order.Back()
+	if back == nil {
+		return zero, false
+	}
+	key := back.Value.(K)
+	p.order.Remove(back)
+	delete(p.elems, key)
+	return key, true
+}
+
+// --- SIEVE -------------------------------------------------------------
+//
+// New keys are pushed to the front, unvisited. A single "hand" walks the
+// list from the back towards the front looking for an unvisited entry,
+// clearing the visited bit of anything it passes over. Hits only flip the
+// visited bit - unlike LRU there is no list reordering on access.
+
+type sieveNode[K comparable] struct {
+	key     K
+	visited bool
+}
+
+type sievePolicy[K comparable] struct {
+	order *list.List
+	elems map[K]*list.Element
+	hand  *list.Element
+}
+
+func newSievePolicy[K comparable]() *sievePolicy[K] {
+	return &sievePolicy[K]{
+		order: list.New(),
+		elems: make(map[K]*list.Element),
+	}
+}
+
+func (p *sievePolicy[K]) OnAccess(key K) {
+	if e, ok := p.elems[key]; ok {
+		e.Value.(*sieveNode[K]).visited = true
+	}
+}
+
+func (p *sievePolicy[K]) OnInsert(key K) {
+	p.elems[key] = p.order.PushFront(&sieveNode[K]{key: key})
+}
+
+// prev returns the element walked towards during eviction, wrapping from
+// the head back to the tail.
+func (p *sievePolicy[K]) prev(e *list.Element) *list.Element {
+	if pr := e.Prev(); pr != nil {
+		return pr
+	}
+	return p.order.Back()
+}
+
+func (p *sievePolicy[K]) OnRemove(key K) {
+	e, ok := p.elems[key]
+	if !ok {
+		return
+	}
+	if p.hand == e {
+		p.hand = p.prev(e)
+		if p.hand == e {
+			p.hand = nil
+		}
+	}
+	p.order.Remove(e)
+	delete(p.elems, key)
+}
+
+func (p *sievePolicy[K]) Evict() (K, bool) {
+	var zero K
+	if p.order.Len() == 0 {
+		return zero, false
+	}
+
+	hand := p.hand
+	if hand == nil {
+		hand = p.order.Back()
+	}
+
+	for hand.Value.(*sieveNode[K]).visited {
+		hand.Value.(*sieveNode[K]).visited = false
+		hand = p.prev(hand)
+	}
+
+	node := hand.Value.(*sieveNode[K])
+	p.hand = p.prev(hand)
+	if p.hand == hand {
+		p.hand = nil
+	}
+	p.order.Remove(hand)
+	delete(p.elems, node.key)
+	return node.key, true
+}
+
+// --- LFU -------------------------------------------------------------
+//
+// Entries are bucketed by access frequency; each bucket is an LRU list so
+// ties within a frequency evict the least-recently-used member. minFreq is
+// maintained incrementally so eviction is O(1).
+
+type lfuNode[K comparable] struct {
+	key  K
+	freq int
+}
+
+type lfuPolicy[K comparable] struct {
+	elems   map[K]*list.Element
+	buckets map[int]*list.List
+	minFreq int
+}
+
+func newLFUPolicy[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{
+		elems:   make(map[K]*list.Element),
+		buckets: make(map[int]*list.List),
+	}
+}
+
+func (p *lfuPolicy[K]) bucket(freq int) *list.List {
+	b, ok := p.buckets[freq]
+	if !ok {
+		b = list.New()
+		p.buckets[freq] = b
+	}
+	return b
+}
+
+func (p *lfuPolicy[K]) detach(e *list.Element, freq int) {
+	b := p.buckets[freq]
+	b.Remove(e)
+	if b.Len() == 0 {
+		delete(p.buckets, freq)
+	}
+}
+
+func (p *lfuPolicy[K]) OnAccess(key K) {
+	e, ok := p.elems[key]
+	if !ok {
+		return
+	}
+	node := e.Value.(*lfuNode[K])
+	oldFreq := node.freq
+	p.detach(e, oldFreq)
+	if p.minFreq == oldFreq && p.buckets[oldFreq] == nil {
+		p.minFreq++
+	}
+
+	node.freq++
+	p.elems[key] = p.bucket(node.freq).PushFront(node)
+}
+
+func (p *lfuPolicy[K]) OnInsert(key K) {
+	node := &lfuNode[K]{key: key, freq: 1}
+	p.elems[key] = p.bucket(1).PushFront(node)
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy[K]) OnRemove(key K) {
+	e, ok := p.elems[key]
+	if !ok {
+		return
+	}
+	node := e.Value.(*lfuNode[K])
+	p.detach(e, node.freq)
+	delete(p.elems, key)
+
+	// The minFreq bucket may have emptied out from under us; recompute it.
+	// This is the one place removal isn't O(1), and only triggers when a
+	// key is deleted or expires out of turn rather than evicted.
+	p.recomputeMinFreqIfEmpty(node.freq)
+}
+
+// recomputeMinFreqIfEmpty rescans p.buckets for the new minimum frequency
+// once the bucket at emptiedFreq might have emptied out - i.e. after a
+// detach that could have been the last node at minFreq. Both OnRemove and
+// Evict call this, since either can empty the min-freq bucket.
+func (p *lfuPolicy[K]) recomputeMinFreqIfEmpty(emptiedFreq int) {
+	if emptiedFreq != p.minFreq || p.buckets[emptiedFreq] != nil {
+		return
+	}
+	p.minFreq = 0
+	for f := range p.buckets {
+		if p.minFreq == 0 || f < p.minFreq {
+			p.minFreq = f
+		}
+	}
+}
+
+func (p *lfuPolicy[K]) Evict() (K, bool) {
+	var zero K
+	b, ok := p.buckets[p.minFreq]
+	if !ok {
+		return zero, false
+	}
+	back := b.Back()
+	node := back.Value.(*lfuNode[K])
+	p.detach(back, node.freq)
+	delete(p.elems, node.key)
+
+	p.recomputeMinFreqIfEmpty(node.freq)
+
+	return node.key, true
+}
+
+// --- ARC -------------------------------------------------------------
+//
+// Adaptive Replacement Cache: T1/T2 hold live entries (seen once, seen
+// again), B1/B2 are ghost lists of recently evicted keys used only to steer
+// the adaptive target size p. Ghost hits are detected in OnInsert, since
+// that's the only point a "new" key is distinguished from a live hit. Evict
+// runs independently of that ghost check (OpenCache always evicts before
+// inserting when the cache is full), so it approximates the textbook
+// REPLACE(x) rule using T1's size relative to p rather than also consulting
+// whether the incoming key is a B2 ghost.
+
+type arcPolicy[K comparable] struct {
+	c       int
+	p       int
+	t1, t2  *list.List
+	b1, b2  *list.List
+	elemsT1 map[K]*list.Element
+	elemsT2 map[K]*list.Element
+	elemsB1 map[K]*list.Element
+	elemsB2 map[K]*list.Element
+}
+
+func newARCPolicy[K comparable](capacity int) *arcPolicy[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &arcPolicy[K]{
+		c:       capacity,
+		t1:      list.New(),
+		t2:      list.New(),
+		b1:      list.New(),
+		b2:      list.New(),
+		elemsT1: make(map[K]*list.Element),
+		elemsT2: make(map[K]*list.Element),
+		elemsB1: make(map[K]*list.Element),
+		elemsB2: make(map[K]*list.Element),
+	}
+}
+
+func (p *arcPolicy[K]) OnAccess(key K) {
+	if e, ok := p.elemsT1[key]; ok {
+		p.t1.Remove(e)
+		delete(p.elemsT1, key)
+		p.elemsT2[key] = p.t2.PushFront(key)
+		return
+	}
+	if e, ok := p.elemsT2[key]; ok {
+		p.t2.MoveToFront(e)
+	}
+}
+
+func (p *arcPolicy[K]) OnInsert(key K) {
+	if e, ok := p.elemsB1[key]; ok {
+		delta := 1
+		if b1Len, b2Len := p.b1.Len(), p.b2.Len(); b1Len > 0 && b2Len > b1Len {
+			delta = b2Len / b1Len
+		}
+		p.p += delta
+		if p.p > p.c {
+			p.p = p.c
+		}
+		p.b1.Remove(e)
+		delete(p.elemsB1, key)
+		p.elemsT2[key] = p.t2.PushFront(key)
+		return
+	}
+	if e, ok := p.elemsB2[key]; ok {
+		delta := 1
+		if b1Len, b2Len := p.b1.Len(), p.b2.Len(); b2Len > 0 && b1Len > b2Len {
+			delta = b1Len / b2Len
+		}
+		p.p -= delta
+		if p.p < 0 {
+			p.p = 0
+		}
+		p.b2.Remove(e)
+		delete(p.elemsB2, key)
+		p.elemsT2[key] = p.t2.PushFront(key)
+		return
+	}
+	p.elemsT1[key] = p.t1.PushFront(key)
+}
+
+func (p *arcPolicy[K]) OnRemove(key K) {
+	if e, ok := p.elemsT1[key]; ok {
+		p.t1.Remove(e)
+		delete(p.elemsT1, key)
+		return
+	}
+	if e, ok := p.elemsT2[key]; ok {
+		p.t2.Remove(e)
+		delete(p.elemsT2, key)
+	}
+}
+
+func trimGhost[K comparable](ghost *list.List, elems map[K]*list.Element, limit int) {
+	for ghost.Len() > limit {
+		back := ghost.Back()
+		delete(elems, back.Value.(K))
+		ghost.Remove(back)
+	}
+}
+
+func (p *arcPolicy[K]) Evict() (K, bool) {
+	var zero K
+
+	if p.t1.Len() > 0 && (p.t1.Len() > p.p || p.t2.Len() == 0) {
+		back := p.t1.Back()
+		key := back.Value.(K)
+		p.t1.Remove(back)
+		delete(p.elemsT1, key)
+		p.elemsB1[key] = p.b1.PushFront(key)
+		trimGhost(p.b1, p.elemsB1, p.c)
+		return key, true
+	}
+
+	if p.t2.Len() > 0 {
+		back := p.t2.Back()
+		key := back.Value.(K)
+		p.t2.Remove(back)
+		delete(p.elemsT2, key)
+		p.elemsB2[key] = p.b2.PushFront(key)
+		trimGhost(p.b2, p.elemsB2, p.c)
+		return key, true
+	}
+
+	return zero, false
+}
@@ -0,0 +1,172 @@
+package cache
+
+// cache/expiry.go
+//
+// Active TTL expiration: a min-heap of pending expirations, keyed by
+// expiresAt, drained by a single background reaper goroutine per cache so
+// that entries stop occupying capacity the moment their TTL lapses rather
+// than waiting for a Get to notice.
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// idleReaperInterval is how long the reaper sleeps when there is nothing
+// scheduled to expire.
+const idleReaperInterval = time.Hour
+
+type expirationItem[K comparable] struct {
+	key       K
+	expiresAt time.Time
+	stale     bool
+	index     int
+}
+
+// expirationQueue is a container/heap min-heap ordered by expiresAt.
+type expirationQueue[K comparable] []*expirationItem[K]
+
+func (q expirationQueue[K]) Len() int { return len(q) }
+
+func (q expirationQueue[K]) Less(i, j int) bool {
+	return q[i].expiresAt.Before(q[j].expiresAt)
+}
+
+func (q expirationQueue[K]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *expirationQueue[K]) Push(x any) {
+	item := x.(*expirationItem[K])
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *expirationQueue[K]) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// startReaper wires up the cancellation context and launches the
+// background reaper goroutine. Called once from every constructor.
+func (kv *OpenCache[K, V]) startReaper() {
+	kv.wake = make(chan struct{}, 1)
+	kv.ctx, kv.cancel = context.WithCancel(context.Background())
+	go kv.runReaper()
+}
+
+// scheduleExpiry pushes a fresh expiration onto the heap for ent, staling
+// out whatever was scheduled for it before. Must be called with kv.Mu held.
+func (kv *OpenCache[K, V]) scheduleExpiry(key K, ent *entry[K, V], expiresAt time.Time) {
+	kv.invalidateExpiry(ent)
+
+	item := &expirationItem[K]{key: key, expiresAt: expiresAt}
+	heap.Push(&kv.expireQueue, item)
+	ent.heapItem = item
+
+	kv.notifyReaper()
+}
+
+// invalidateExpiry marks ent's current heap entry (if any) stale so the
+// reaper skips over it instead of acting on outdated expiry information.
+// Must be called with kv.Mu held.
+func (kv *OpenCache[K, V]) invalidateExpiry(ent *entry[K, V]) {
+	if ent.heapItem != nil {
+		ent.heapItem.stale = true
+		ent.heapItem = nil
+	}
+}
+
+func (kv *OpenCache[K, V]) notifyReaper() {
+	select {
+	case kv.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runReaper sleeps until the next scheduled expiration, then drains
+// everything due. It exits once Close cancels kv.ctx.
+func (kv *OpenCache[K, V]) runReaper() {
+	timer := time.NewTimer(idleReaperInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-kv.ctx.Done():
+			return
+		case <-kv.wake:
+		case <-timer.C:
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		kv.Mu.Lock()
+		next := kv.drainExpired()
+		kv.Mu.Unlock()
+
+		timer.Reset(next)
+	}
+}
+
+// drainExpired removes every entry whose TTL has lapsed and returns how
+// long the reaper should sleep before it needs to look again. Must be
+// called with kv.Mu held.
+func (kv *OpenCache[K, V]) drainExpired() time.Duration {
+	now := time.Now()
+
+	for kv.expireQueue.Len() > 0 {
+		item := kv.expireQueue[0]
+
+		if item.stale {
+			heap.Pop(&kv.expireQueue)
+			continue
+		}
+
+		if item.expiresAt.After(now) {
+			return item.expiresAt.Sub(now)
+		}
+
+		heap.Pop(&kv.expireQueue)
+
+		ent, ok := kv.Cache[item.key]
+		if !ok || ent.heapItem != item {
+			// Already replaced or removed through some other path.
+			continue
+		}
+
+		delete(kv.Cache, item.key)
+		kv.Policy.OnRemove(item.key)
+		if kv.Sizer != nil {
+			kv.CurrentBytes -= ent.size
+		}
+
+		kv.Logger.Debugw("Deleted entry due to active TTL reaper",
+			"key", item.key,
+			"expiresAt", item.expiresAt,
+		)
+		kv.emitEviction(item.key, ent.value, ReasonTTL)
+		kv.emitExpiration(item.key, ent.value)
+
+		if kv.Persistent {
+			if logEntry, err := kv.makeLogEntry("EXPIRE", item.key, ent.value, nil); err == nil {
+				kv.AppendToLog(logEntry)
+			} else {
+				kv.Logger.Errorw("Failed to encode AOF entry", "key", item.key, "error", err)
+			}
+		}
+	}
+
+	return idleReaperInterval
+}
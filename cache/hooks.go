@@ -0,0 +1,152 @@
+package cache
+
+// cache/hooks.go
+//
+// Lifecycle hooks let callers observe cache events (metrics, cascading
+// invalidations, write-back to a backing store) without coupling into the
+// locking internals. Hooks are queued onto a channel and run on a single
+// dedicated worker goroutine, outside of kv.Mu, so a hook that calls back
+// into the cache (e.g. Get/Set on a different key) can never deadlock
+// against the operation that triggered it.
+
+// EvictionReason explains why an entry left the cache via OnEviction.
+type EvictionReason int
+
+const (
+	ReasonCapacity EvictionReason = iota
+	ReasonBytes
+	ReasonTTL
+	ReasonManual
+	ReasonReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonBytes:
+		return "bytes"
+	case ReasonTTL:
+		return "ttl"
+	case ReasonManual:
+		return "manual"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// hookQueueSize bounds how many pending hook calls can back up before new
+// ones are dropped (logged) rather than risking unbounded memory growth.
+const hookQueueSize = 1024
+
+type hookKind int
+
+const (
+	hookInsertion hookKind = iota
+	hookEviction
+	hookExpiration
+	hookDeletion
+)
+
+type hookEvent[K comparable, V any] struct {
+	kind   hookKind
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// Option configures an OpenCache at construction time. See WithOnInsertion,
+// WithOnEviction, WithOnExpiration, and WithOnDeletion.
+type Option[K comparable, V any] func(*OpenCache[K, V])
+
+// WithOnInsertion registers a callback fired whenever a brand-new key is
+// added to the cache.
+func WithOnInsertion[K comparable, V any](fn func(key K, value V)) Option[K, V] {
+	return func(kv *OpenCache[K, V]) { kv.onInsertion = fn }
+}
+
+// WithOnEviction registers a callback fired whenever an entry leaves the
+// cache for any reason (capacity, byte budget, TTL, manual delete, or
+// being replaced by a new value for the same key).
+func WithOnEviction[K comparable, V any](fn func(key K, value V, reason EvictionReason)) Option[K, V] {
+	return func(kv *OpenCache[K, V]) { kv.onEviction = fn }
+}
+
+// WithOnExpiration registers a callback fired specifically when an entry is
+// reclaimed because its TTL lapsed.
+func WithOnExpiration[K comparable, V any](fn func(key K, value V)) Option[K, V] {
+	return func(kv *OpenCache[K, V]) { kv.onExpiration = fn }
+}
+
+// WithOnDeletion registers a callback fired specifically when a caller
+// removes an entry via Delete.
+func WithOnDeletion[K comparable, V any](fn func(key K, value V)) Option[K, V] {
+	return func(kv *OpenCache[K, V]) { kv.onDeletion = fn }
+}
+
+// startHooks allocates the hook queue and launches its worker. Called once
+// from every constructor, after options have been applied.
+func (kv *OpenCache[K, V]) startHooks() {
+	kv.hookCh = make(chan hookEvent[K, V], hookQueueSize)
+	go kv.runHooks()
+}
+
+func (kv *OpenCache[K, V]) runHooks() {
+	for {
+		select {
+		case <-kv.ctx.Done():
+			return
+		case ev := <-kv.hookCh:
+			kv.dispatchHook(ev)
+		}
+	}
+}
+
+func (kv *OpenCache[K, V]) dispatchHook(ev hookEvent[K, V]) {
+	switch ev.kind {
+	case hookInsertion:
+		if kv.onInsertion != nil {
+			kv.onInsertion(ev.key, ev.value)
+		}
+	case hookEviction:
+		if kv.onEviction != nil {
+			kv.onEviction(ev.key, ev.value, ev.reason)
+		}
+	case hookExpiration:
+		if kv.onExpiration != nil {
+			kv.onExpiration(ev.key, ev.value)
+		}
+	case hookDeletion:
+		if kv.onDeletion != nil {
+			kv.onDeletion(ev.key, ev.value)
+		}
+	}
+}
+
+// emit queues a hook event. It never blocks: if the queue is full the event
+// is dropped and logged, rather than stalling the caller holding kv.Mu.
+func (kv *OpenCache[K, V]) emit(ev hookEvent[K, V]) {
+	select {
+	case kv.hookCh <- ev:
+	default:
+		kv.Logger.Warnw("Dropped cache event hook, queue full", "key", ev.key)
+	}
+}
+
+func (kv *OpenCache[K, V]) emitInsertion(key K, value V) {
+	kv.emit(hookEvent[K, V]{kind: hookInsertion, key: key, value: value})
+}
+
+func (kv *OpenCache[K, V]) emitEviction(key K, value V, reason EvictionReason) {
+	kv.emit(hookEvent[K, V]{kind: hookEviction, key: key, value: value, reason: reason})
+}
+
+func (kv *OpenCache[K, V]) emitExpiration(key K, value V) {
+	kv.emit(hookEvent[K, V]{kind: hookExpiration, key: key, value: value})
+}
+
+func (kv *OpenCache[K, V]) emitDeletion(key K, value V) {
+	kv.emit(hookEvent[K, V]{kind: hookDeletion, key: key, value: value})
+}
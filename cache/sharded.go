@@ -0,0 +1,260 @@
+package cache
+
+// cache/sharded.go
+//
+// ShardedCache spreads the key space across N independent OpenCache
+// shards, each with its own mutex, background reaper, and hook worker,
+// so concurrent Get/Set/Delete calls for different keys never contend on
+// the same lock. Each shard persists to its own AOF file so replay can
+// run per-shard without reconstructing a global write ordering.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"hash/maphash"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// defaultShardCount is what ShardedCache falls back to when the caller
+// passes 0 for numShards: runtime.GOMAXPROCS(0)*4, rounded up to a power
+// of two, with a floor of 16.
+func defaultShardCount() int {
+	n := runtime.GOMAXPROCS(0) * 4
+	if n < 16 {
+		n = 16
+	}
+	return nextPow2(n)
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// ShardedCache wraps N OpenCache shards behind a single Get/Set/Delete
+// API, routing each key to its shard by hash so independent keys never
+// block on the same mutex.
+type ShardedCache[K comparable, V any] struct {
+	shards []*OpenCache[K, V]
+	mask   uint64
+	seed   maphash.Seed
+}
+
+// NewShardedCache builds a ShardedCache of numShards shards (rounded up
+// to a power of two; 0 picks defaultShardCount), each constructed via
+// NewOpenCache with perShardCapacity, policy, and opts. If persistent,
+// shard i persists to
+// filepath.Join(logDir, fmt.Sprintf("appendonly-%04x.aof", i)).
+func NewShardedCache[K comparable, V any](numShards int, perShardCapacity int, persistent bool, logDir string, policy Policy, opts ...Option[K, V]) *ShardedCache[K, V] {
+	if numShards <= 0 {
+		numShards = defaultShardCount()
+	} else {
+		numShards = nextPow2(numShards)
+	}
+
+	sc := &ShardedCache[K, V]{
+		shards: make([]*OpenCache[K, V], numShards),
+		mask:   uint64(numShards - 1),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range sc.shards {
+		logPath := ""
+		if persistent {
+			logPath = filepath.Join(logDir, fmt.Sprintf("appendonly-%04x.aof", i))
+		}
+		sc.shards[i] = NewOpenCache[K, V](perShardCapacity, persistent, logPath, policy, opts...)
+	}
+	return sc
+}
+
+func (sc *ShardedCache[K, V]) shardFor(key K) *OpenCache[K, V] {
+	return sc.shards[sc.hashKey(key)&sc.mask]
+}
+
+// hashKey routes strings and byte slices through maphash.Hash, which is
+// built for exactly this (fast, non-cryptographic, seeded per process).
+// Any other comparable key type falls back to a reflect-driven FNV hash.
+func (sc *ShardedCache[K, V]) hashKey(key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		var h maphash.Hash
+		h.SetSeed(sc.seed)
+		h.WriteString(k)
+		return h.Sum64()
+	case []byte:
+		var h maphash.Hash
+		h.SetSeed(sc.seed)
+		h.Write(k)
+		return h.Sum64()
+	default:
+		return reflectFNVHash(key)
+	}
+}
+
+// reflectFNVHash is the fallback hash for key types maphash.Hash can't
+// consume directly: it walks the key's fields with reflection (mirroring
+// sizeOfValue in size.go) and feeds their bytes into an FNV-1a hash.
+func reflectFNVHash[K comparable](key K) uint64 {
+	h := fnv.New64a()
+	writeReflectBytes(h, reflect.ValueOf(key))
+	return h.Sum64()
+}
+
+func writeReflectBytes(h hash.Hash64, rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.String:
+		h.Write([]byte(rv.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(rv.Int()))
+		h.Write(buf[:])
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], rv.Uint())
+		h.Write(buf[:])
+	case reflect.Float32, reflect.Float64:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(rv.Float()))
+		h.Write(buf[:])
+	case reflect.Bool:
+		if rv.Bool() {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			writeReflectBytes(h, rv.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			writeReflectBytes(h, rv.Field(i))
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			writeReflectBytes(h, rv.Elem())
+		}
+	default:
+		h.Write([]byte(fmt.Sprintf("%v", rv.Interface())))
+	}
+}
+
+// Get forwards to the shard that owns key.
+func (sc *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return sc.shardFor(key).Get(key)
+}
+
+// Set forwards to the shard that owns key.
+func (sc *ShardedCache[K, V]) Set(key K, value V, ttl *time.Duration) bool {
+	return sc.shardFor(key).Set(key, value, ttl)
+}
+
+// Delete forwards to the shard that owns key.
+func (sc *ShardedCache[K, V]) Delete(key K) bool {
+	return sc.shardFor(key).Delete(key)
+}
+
+// Len sums the length of every shard.
+func (sc *ShardedCache[K, V]) Len() int {
+	total := 0
+	for _, shard := range sc.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// Close tears down every shard's background goroutines.
+func (sc *ShardedCache[K, V]) Close() {
+	for _, shard := range sc.shards {
+		shard.Close()
+	}
+}
+
+// ReplayDir repopulates every shard from its AOF file under dir. Each
+// line is re-hashed against the live shard count rather than trusted to
+// already belong to the shard it was read from, so replay stays correct
+// even if the shard count changed since the files were written.
+func (sc *ShardedCache[K, V]) ReplayDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "appendonly-*.aof"))
+	if err != nil {
+		return err
+	}
+
+	// Bug fix-Temporarily disable persistence during replay
+	prevPersistent := make([]bool, len(sc.shards))
+	for i, shard := range sc.shards {
+		shard.Mu.Lock()
+		prevPersistent[i] = shard.Persistent
+		shard.Persistent = false
+		shard.Mu.Unlock()
+	}
+	defer func() {
+		for i, shard := range sc.shards {
+			shard.Mu.Lock()
+			shard.Persistent = prevPersistent[i]
+			shard.Mu.Unlock()
+		}
+	}()
+
+	for _, file := range files {
+		if err := sc.replayFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *ShardedCache[K, V]) replayFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var logEntry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &logEntry); err != nil {
+			continue
+		}
+
+		key, err := sc.shards[0].UnmarshalKey(logEntry.Key)
+		if err != nil {
+			sc.shards[0].Logger.Errorw("Failed to decode AOF key", "key", logEntry.Key, "error", err)
+			continue
+		}
+		shard := sc.shardFor(key)
+
+		switch logEntry.Op {
+		case "SET":
+			var value V
+			if len(logEntry.Value) > 0 {
+				if err := json.Unmarshal(logEntry.Value, &value); err != nil {
+					shard.Logger.Errorw("Failed to decode AOF value", "key", logEntry.Key, "error", err)
+					continue
+				}
+			}
+			var ttl *time.Duration
+			if logEntry.TTLms > 0 {
+				t := time.Duration(logEntry.TTLms) * time.Millisecond
+				ttl = &t
+			}
+			shard.Set(key, value, ttl)
+		case "DELETE", "EXPIRE":
+			shard.Delete(key)
+		}
+	}
+	return scanner.Err()
+}
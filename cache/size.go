@@ -0,0 +1,91 @@
+package cache
+
+// cache/size.go
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses human-readable byte sizes such as "64MB", "1.5GB", or a
+// bare byte count like "1024". Units are binary (1KB == 1024 bytes) and
+// case-insensitive.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return 0, fmt.Errorf("cache: empty size string")
+	}
+
+	upper := strings.ToUpper(trimmed)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(upper, unit.suffix) {
+			numPart := strings.TrimSpace(trimmed[:len(trimmed)-len(unit.suffix)])
+			val, err := strconv.ParseFloat(numPart, 64)
+			if err != nil {
+				return 0, fmt.Errorf("cache: invalid size %q: %w", s, err)
+			}
+			return int64(val * float64(unit.factor)), nil
+		}
+	}
+
+	val, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cache: invalid size %q: %w", s, err)
+	}
+	return val, nil
+}
+
+// DefaultSizer estimates the in-memory footprint of a key/value pair. It
+// starts from unsafe.Sizeof and recurses into strings, slices, and maps,
+// whose backing storage isn't captured by Sizeof alone.
+func DefaultSizer(k, v any) int64 {
+	return sizeOf(k) + sizeOf(v)
+}
+
+func sizeOf(x any) int64 {
+	if x == nil {
+		return 0
+	}
+	return int64(unsafe.Sizeof(x)) + sizeOfValue(reflect.ValueOf(x))
+}
+
+func sizeOfValue(rv reflect.Value) int64 {
+	switch rv.Kind() {
+	case reflect.String:
+		return int64(rv.Len())
+	case reflect.Slice, reflect.Array:
+		var total int64
+		for i := 0; i < rv.Len(); i++ {
+			total += sizeOfValue(rv.Index(i))
+		}
+		return total
+	case reflect.Map:
+		var total int64
+		for _, key := range rv.MapKeys() {
+			total += sizeOfValue(key)
+			total += sizeOfValue(rv.MapIndex(key))
+		}
+		return total
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return 0
+		}
+		return sizeOfValue(rv.Elem())
+	default:
+		return int64(rv.Type().Size())
+	}
+}
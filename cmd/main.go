@@ -3,7 +3,7 @@ package main
 import "github.com/evanjt06/opencache/cache"
 
 func main() {
-	c := cache.NewOpenCache(2, false, "")
+	c := cache.NewOpenCache[string, int](2, false, "", cache.LRU)
 
 	c.Set("a", 1, nil)
 	c.Set("b", 2, nil)
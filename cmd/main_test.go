@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestSetAndGet(t *testing.T) {
-	c := cache.NewOpenCache(2, false, "")
+	c := cache.NewOpenCache[string, int](2, false, "", cache.LRU)
 
 	c.Set("a", 1, nil)
 	c.Set("b", 2, nil)
@@ -24,11 +25,11 @@ func TestSetAndGet(t *testing.T) {
 		t.Errorf("Expected 'b' to be 2, got %v", v)
 	}
 
-	c.Log()
+	c.Print()
 }
 
 func TestLRUEviction(t *testing.T) {
-	c := cache.NewOpenCache(2, false, "")
+	c := cache.NewOpenCache[string, int](2, false, "", cache.LRU)
 
 	c.Set("a", 1, nil)
 	c.Set("b", 2, nil)
@@ -46,11 +47,137 @@ func TestLRUEviction(t *testing.T) {
 	if _, ok := c.Get("c"); !ok {
 		t.Error("Expected 'c' to be present")
 	}
-	c.Log()
+	c.Print()
+}
+
+func TestSieveEviction(t *testing.T) {
+	c := cache.NewOpenCache[string, int](2, false, "", cache.SIEVE)
+
+	c.Set("a", 1, nil)
+	c.Set("b", 2, nil)
+	c.Get("a")         // mark 'a' visited
+	c.Set("c", 3, nil) // 'a' survives the first sweep unvisited, 'b' is evicted
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected 'b' to be evicted")
+	}
+
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected 'a' to still be present")
+	}
+
+	if _, ok := c.Get("c"); !ok {
+		t.Error("Expected 'c' to be present")
+	}
+	c.Print()
+}
+
+func TestLFUEviction(t *testing.T) {
+	c := cache.NewOpenCache[string, int](2, false, "", cache.LFU)
+
+	c.Set("a", 1, nil)
+	c.Set("b", 2, nil)
+	c.Get("a") // 'a' now has higher frequency than 'b'
+	c.Set("c", 3, nil)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected least-frequently-used 'b' to be evicted")
+	}
+
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected 'a' to still be present")
+	}
+	c.Print()
+}
+
+func TestARCEviction(t *testing.T) {
+	c := cache.NewOpenCache[string, int](2, false, "", cache.ARC)
+
+	c.Set("a", 1, nil)
+	c.Set("b", 2, nil)
+	c.Set("c", 3, nil)
+
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries to remain, got %d", c.Len())
+	}
+	c.Print()
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"64MB": 64 * 1024 * 1024,
+		"1GB":  1024 * 1024 * 1024,
+		"512B": 512,
+		"2048": 2048,
+	}
+
+	for input, want := range cases {
+		got, err := cache.ParseSize(input)
+		if err != nil {
+			t.Errorf("ParseSize(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", input, got, want)
+		}
+	}
+
+	if _, err := cache.ParseSize("not-a-size"); err == nil {
+		t.Error("Expected ParseSize to reject an invalid size string")
+	}
+}
+
+func TestByteBoundedEviction(t *testing.T) {
+	maxBytes, err := cache.ParseSize("1KB")
+	if err != nil {
+		t.Fatalf("ParseSize failed: %v", err)
+	}
+
+	c := cache.NewOpenCacheWithBytes[string, string](maxBytes, nil, false, "", cache.LRU)
+
+	big := strings.Repeat("x", 512)
+	c.Set("a", big, nil)
+	c.Set("b", big, nil)
+	c.Set("c", big, nil) // should push the cache over budget and evict 'a'
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected 'a' to be evicted once the byte budget was exceeded")
+	}
+
+	if c.BytesUsed() > maxBytes {
+		t.Errorf("Expected BytesUsed (%d) to stay under maxBytes (%d)", c.BytesUsed(), maxBytes)
+	}
+}
+
+func TestByteBoundedEvictionLFU(t *testing.T) {
+	const maxBytes = 300
+
+	c := cache.NewOpenCacheWithBytes[string, string](maxBytes, nil, false, "", cache.LFU)
+
+	small := strings.Repeat("x", 40)
+	c.Set("a", small, nil) // stays at freq 1 - the coldest entry
+	c.Set("b", small, nil)
+	c.Get("b") // bumps 'b' to freq 2, leaving 'a' alone in the freq-1 bucket
+	c.Set("c", small, nil)
+	c.Get("c") // bumps 'c' to freq 2 as well
+
+	// Grow 'c' well past maxBytes in a single Set. enforceBudget now needs
+	// two evictions to get back under budget: 'a' (freq 1) first, then 'b'
+	// (freq 2). The first Evict() empties the freq-1 bucket entirely, which
+	// is exactly what tripped the LFU minFreq bug - without recomputing
+	// minFreq, the second Evict() call found no bucket at the stale minFreq
+	// and returned false, leaving the cache over budget with 'b' still
+	// cached.
+	huge := strings.Repeat("x", 250)
+	c.Set("c", huge, nil)
+
+	if got := c.BytesUsed(); got > maxBytes {
+		t.Errorf("Expected BytesUsed (%d) to stay under maxBytes (%d) after a multi-eviction Set", got, maxBytes)
+	}
 }
 
 func TestTTLExpiration(t *testing.T) {
-	c := cache.NewOpenCache(2, false, "")
+	c := cache.NewOpenCache[string, string](2, false, "", cache.LRU)
 
 	ttl := 1 * time.Second
 	c.Set("x", "expiring", &ttl)
@@ -61,11 +188,244 @@ func TestTTLExpiration(t *testing.T) {
 		t.Error("Expected 'x' to have expired")
 	}
 
-	c.Log()
+	c.Print()
+}
+
+func TestActiveTTLReaper(t *testing.T) {
+	c := cache.NewOpenCache[string, string](2, false, "", cache.LRU)
+	defer c.Close()
+
+	ttl := 100 * time.Millisecond
+	c.Set("x", "expiring", &ttl)
+
+	time.Sleep(500 * time.Millisecond)
+
+	// The reaper goroutine should have already reclaimed 'x' without Get
+	// ever being called, so Len must reflect that reclamation directly.
+	if n := c.Len(); n != 0 {
+		t.Errorf("Expected the reaper to have reclaimed the expired entry, cache still holds %d", n)
+	}
+}
+
+func TestEventHooks(t *testing.T) {
+	var mu sync.Mutex
+	var inserted, deleted []string
+	var evicted []string
+	var expired []string
+
+	c := cache.NewOpenCache[string, string](1, false, "", cache.LRU,
+		cache.WithOnInsertion[string, string](func(key, value string) {
+			mu.Lock()
+			defer mu.Unlock()
+			inserted = append(inserted, key)
+		}),
+		cache.WithOnEviction[string, string](func(key, value string, reason cache.EvictionReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			evicted = append(evicted, key+":"+reason.String())
+		}),
+		cache.WithOnExpiration[string, string](func(key, value string) {
+			mu.Lock()
+			defer mu.Unlock()
+			expired = append(expired, key)
+		}),
+		cache.WithOnDeletion[string, string](func(key, value string) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, key)
+		}),
+	)
+	defer c.Close()
+
+	c.Set("a", "1", nil)
+	c.Set("b", "2", nil) // evicts 'a' by capacity
+	c.Delete("b")
+
+	ttl := 100 * time.Millisecond
+	c.Set("c", "3", &ttl)
+	time.Sleep(500 * time.Millisecond) // reaper should reclaim 'c'
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(inserted) != 3 || inserted[0] != "a" || inserted[1] != "b" || inserted[2] != "c" {
+		t.Errorf("Expected insertion hooks for a, b, c in order, got %v", inserted)
+	}
+	if len(deleted) != 1 || deleted[0] != "b" {
+		t.Errorf("Expected deletion hook for 'b', got %v", deleted)
+	}
+	if len(expired) != 1 || expired[0] != "c" {
+		t.Errorf("Expected expiration hook for 'c', got %v", expired)
+	}
+
+	wantEvictions := map[string]bool{"a:capacity": true, "b:manual": true, "c:ttl": true}
+	if len(evicted) != len(wantEvictions) {
+		t.Fatalf("Expected %d eviction hooks, got %v", len(wantEvictions), evicted)
+	}
+	for _, e := range evicted {
+		if !wantEvictions[e] {
+			t.Errorf("Unexpected eviction hook entry %q", e)
+		}
+	}
+}
+
+func TestRewriteAOF(t *testing.T) {
+	logFile := "test_rewrite.aof"
+	os.Remove(logFile)
+	defer os.Remove(logFile)
+
+	c := cache.NewOpenCache[string, string](10, true, logFile, cache.LRU)
+
+	c.Set("user", "evan", nil)
+	c.Set("user", "evan2", nil) // superseded SET, should be dropped by the rewrite
+	c.Set("temp", "gone", nil)
+	c.Delete("temp") // SET+DELETE pair, should be dropped by the rewrite
+	ttl := time.Minute
+	c.Set("session", "abc123", &ttl)
+
+	if err := c.RewriteAOF(logFile); err != nil {
+		t.Fatalf("RewriteAOF failed: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read rewritten AOF: %v", err)
+	}
+	lines := strings.TrimRight(string(data), "\n")
+	if got := strings.Count(lines, "\n") + 1; got != 2 {
+		t.Errorf("Expected the rewritten AOF to hold 2 lines (one per live key), got %d:\n%s", got, lines)
+	}
+
+	c2 := cache.NewOpenCache[string, string](10, true, logFile, cache.LRU)
+	if err := c2.ReplayLog(logFile); err != nil {
+		t.Fatalf("ReplayLog of rewritten AOF failed: %v", err)
+	}
+
+	if v, ok := c2.Get("user"); !ok || v != "evan2" {
+		t.Errorf("Expected 'user' to replay as 'evan2', got %v", v)
+	}
+	if v, ok := c2.Get("session"); !ok || v != "abc123" {
+		t.Errorf("Expected 'session' to replay as 'abc123', got %v", v)
+	}
+	if _, ok := c2.Get("temp"); ok {
+		t.Error("Expected 'temp' to be absent after replaying the rewritten AOF")
+	}
+}
+
+func TestAutoRewrite(t *testing.T) {
+	logFile := "test_autorewrite.aof"
+	os.Remove(logFile)
+	defer os.Remove(logFile)
+
+	c := cache.NewOpenCache[string, string](100, true, logFile, cache.LRU,
+		cache.WithAutoRewrite[string, string](256),
+	)
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("key-%d", i), fmt.Sprintf("key-%d", i), nil) // same key re-set to inflate the log
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := os.ReadFile(logFile)
+		if err == nil && strings.Count(string(data), "\n") <= 100 {
+			return // rewrite happened, log shrank back down
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Expected background auto-rewrite to compact the AOF")
+}
+
+func TestShardedCache(t *testing.T) {
+	c := cache.NewShardedCache[string, int](4, 100, false, "", cache.LRU)
+	defer c.Close()
+
+	for i := 0; i < 200; i++ {
+		c.Set(fmt.Sprintf("key-%d", i), i, nil)
+	}
+
+	for i := 0; i < 200; i++ {
+		v, ok := c.Get(fmt.Sprintf("key-%d", i))
+		if !ok || v != i {
+			t.Errorf("Expected key-%d to be %d, got %v", i, i, v)
+		}
+	}
+
+	if ok := c.Delete("key-0"); !ok {
+		t.Error("Expected Delete to return true for an existing key")
+	}
+	if _, ok := c.Get("key-0"); ok {
+		t.Error("Expected 'key-0' to be deleted")
+	}
+
+	if n := c.Len(); n != 199 {
+		t.Errorf("Expected 199 entries across all shards, got %d", n)
+	}
+}
+
+func TestShardedCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	c := cache.NewShardedCache[string, string](4, 100, true, dir, cache.LRU)
+	c.Set("user", "evan", nil)
+	c.Set("temp", "gone", nil)
+	c.Delete("temp")
+	ttl := time.Minute
+	c.Set("session", "abc123", &ttl)
+	c.Close()
+
+	c2 := cache.NewShardedCache[string, string](4, 100, true, dir, cache.LRU)
+	defer c2.Close()
+	if err := c2.ReplayDir(dir); err != nil {
+		t.Fatalf("ReplayDir failed: %v", err)
+	}
+
+	if v, ok := c2.Get("user"); !ok || v != "evan" {
+		t.Errorf("Expected 'user' to replay as 'evan', got %v", v)
+	}
+	if v, ok := c2.Get("session"); !ok || v != "abc123" {
+		t.Errorf("Expected 'session' to replay as 'abc123', got %v", v)
+	}
+	if _, ok := c2.Get("temp"); ok {
+		t.Error("Expected 'temp' to be absent after replay")
+	}
+}
+
+// BenchmarkOpenCacheConcurrent and BenchmarkShardedCacheConcurrent are
+// meant to be compared with `go test -bench . -cpu=1,2,4,8 ./cmd`: the
+// single-mutex cache flattens out as -cpu grows while the sharded cache
+// keeps scaling, since unrelated keys no longer contend on one lock.
+func BenchmarkOpenCacheConcurrent(b *testing.B) {
+	c := cache.NewOpenCache[int, int](10000, false, "", cache.LRU)
+	defer c.Close()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(i, i, nil)
+			c.Get(i)
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedCacheConcurrent(b *testing.B) {
+	c := cache.NewShardedCache[int, int](0, 10000, false, "", cache.LRU)
+	defer c.Close()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(i, i, nil)
+			c.Get(i)
+			i++
+		}
+	})
 }
 
 func TestDelete(t *testing.T) {
-	c := cache.NewOpenCache(1, false, "")
+	c := cache.NewOpenCache[string, string](1, false, "", cache.LRU)
 
 	c.Set("foo", "bar", nil)
 	ok := c.Delete("foo")
@@ -76,11 +436,11 @@ func TestDelete(t *testing.T) {
 	if _, ok := c.Get("foo"); ok {
 		t.Error("Expected 'foo' to be deleted")
 	}
-	c.Log()
+	c.Print()
 }
 
 func TestConcurrentAccess(t *testing.T) {
-	c := cache.NewOpenCache(100, false, "")
+	c := cache.NewOpenCache[string, int](100, false, "", cache.LRU)
 	var wg sync.WaitGroup
 
 	setAndGet := func(key string, val int) {
@@ -99,7 +459,7 @@ func TestConcurrentAccess(t *testing.T) {
 
 	wg.Wait()
 
-	c.Log()
+	c.Print()
 }
 
 func TestPersistentCache(t *testing.T) {
@@ -107,19 +467,19 @@ func TestPersistentCache(t *testing.T) {
 	os.Remove(logFile) // clean up previous log
 
 	// 1. Create persistent cache and write some data
-	c := cache.NewOpenCache(10, true, logFile)
+	c := cache.NewOpenCache[string, string](10, true, logFile, cache.LRU)
 
 	c.Delete("hi")
 	c.Set("user", "evan", nil)
 	ttl := 2 * time.Second
 	c.Set("session", "abc123", &ttl)
 	c.Delete("user")
-	c.Log()
+	c.Print()
 
 	// 2. Reconstruct from log
-	c2 := cache.NewOpenCache(10, true, logFile)
+	c2 := cache.NewOpenCache[string, string](10, true, logFile, cache.LRU)
 	if err := c2.ReplayLog(logFile); err != nil {
 		t.Fatalf("ReplayLog failed: %v", err)
 	}
-	c2.Log()
+	c2.Print()
 }
@@ -5,6 +5,10 @@ import (
 	"reflect"
 )
 
+// ValidateKey guards against nil and dynamically non-comparable keys. It is
+// no longer called from cache.OpenCache[K, V], since a comparable type
+// parameter rules both cases out at compile time; it remains exported for
+// callers still on the legacy interface{}-keyed path.
 func ValidateKey(key interface{}) error {
 	if key == nil {
 		return fmt.Errorf("key cannot be nil")